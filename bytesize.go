@@ -20,12 +20,27 @@
 //	.3	"B",   "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"
 // Other precision values are treated as .3, giving the default suffixes.
 //
+// Adding a '+' flag (e.g. "%+v") selects SI (decimal, base-1000) units
+// instead of the default IEC (binary, base-1024) ones, giving suffixes "B",
+// "kB", "MB", "GB", "TB", "PB", "EB" rather than "B", "KiB", "MiB", "GiB",
+// "TiB", "PiB", "EiB"; precision affects the SI suffixes the same way as
+// above, except that .2 and .3 both give the "kB"-style forms since there is
+// no SI equivalent of "KiB". FormatSI provides the same decimal formatting
+// without going through fmt.
+//
 // ByteSize has a String() method, which always uses the default suffixes.
 //
 // For completeness, ByteSize also has a GoString method, which has the same
 // effect as fmt.Sprintf("ByteSize(%d)", int64(value)).
 //
-// This package exports only one directly-visible name, ByteSize.
+// ParseByteSize parses strings in the forms String/Format produce (at any
+// precision), plus the GoString form, into a ByteSize (see ParseByteSize for
+// which values round-trip exactly); *ByteSize also has a Set
+// method, so it satisfies flag.Value and can be used directly with
+// flag.Var.
+//
+// This package's main export is ByteSize; RoundingMode, BinaryPrefixLetter
+// and the other exported names support it or the bytesize/i18n subpackage.
 //
 // When using this package, you may want to define a type alias, like this:
 //	type ByteSize = bytesize.ByteSize
@@ -34,15 +49,61 @@
 package bytesize
 
 // PROPOSAL: Should internationalize this		// ???FIXME
-// PROPOSAL: could also implement fmt.Scanner
 
 import (
 	"bytes"
 	"fmt"
+	"math/bits"
 	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
+// binaryPrefixLetters[level] gives the suffix letter for a power-of-1024
+// level, running from level 0 ("no suffix", i.e. bytes) up to level 6 (Ei).
+// formatByteSize and the parsing functions below share this table so that
+// parsing a formatted ByteSize always round-trips.
+const binaryPrefixLetters = "!KMGTPE"
+
+// A RoundingMode controls how a ByteSize's mantissa is rounded to the
+// precision its suffix implies, wherever formatting doesn't land on an
+// exact multiple of the chosen unit.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds a value exactly halfway between two representable
+	// mantissas away from zero. This is bytesize's historical behaviour,
+	// and remains the default.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds a value exactly halfway between two
+	// representable mantissas to whichever is even — "banker's rounding".
+	RoundHalfEven
+	// RoundDown truncates toward zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundCeiling rounds toward positive infinity.
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor
+)
+
+// defaultRoundingMode is the RoundingMode used by String, by the %v/%s verbs
+// of Format, and by FormatSI, unless a caller asks for a different one via
+// FormatWith/FormatSIWith. SetDefaultRoundingMode changes it.
+var defaultRoundingMode = RoundHalfUp
+
+// SetDefaultRoundingMode changes the RoundingMode used by String, by the
+// %v/%s verbs of Format, and by FormatSI/Split. It affects every ByteSize
+// value formatted afterwards, process-wide, so it's meant to be called once
+// during program startup (e.g. for an application that always wants
+// RoundDown for quota displays) rather than varied per call — use
+// FormatWith/FormatSIWith for that. It is not safe to call concurrently
+// with formatting a ByteSize.
+func SetDefaultRoundingMode(m RoundingMode) {
+	defaultRoundingMode = m
+}
+
 // A ByteSize is a number of bytes, possibly negative.
 //
 type ByteSize int64
@@ -56,7 +117,20 @@ type ByteSize int64
 // rather than this method when printing ByteSize values.)
 //
 func (n ByteSize) String() string {
-	return string(formatByteSize(int64(n), 3))
+	return string(formatByteSize(int64(n), 3, defaultRoundingMode))
+}
+
+// FormatWith is the IEC (base-1024) equivalent of String, but with an
+// explicit precision and RoundingMode rather than always 3 and the current
+// default RoundingMode; see the package documentation for what precision
+// selects. A prec above 3 is treated as 3, and a negative one as 0.
+func (n ByteSize) FormatWith(prec int, mode RoundingMode) string {
+	if prec > 3 {
+		prec = 3
+	} else if prec < 0 {
+		prec = 0
+	}
+	return string(formatByteSize(int64(n), prec, mode))
 }
 
 // The GoString() method is equivalent to formatting the underlying int64 value
@@ -84,6 +158,9 @@ func (n ByteSize) GoString() string {
 // precision specifier format verb affects which suffixes are used, not how many
 // digits are output.
 //
+// A '+' flag (e.g. "%+v") selects SI (base-1000) suffixes instead of the
+// default IEC (base-1024) ones; see FormatSI.
+//
 func (b ByteSize) Format(f fmt.State, verb rune) {
 	nBytes := int64(b)
 	if verb == 'v' {
@@ -91,7 +168,7 @@ func (b ByteSize) Format(f fmt.State, verb rune) {
 			fmt.Fprintf(f, "ByteSize(%d)", nBytes)
 			return
 		}
-		// else use formatByteSize()
+		// else use formatByteSize() or formatByteSizeSI()
 	} else if verb != 's' {
 		fmt.Fprintf(f, equivalentFormat(f, verb), nBytes)
 		return
@@ -104,7 +181,12 @@ func (b ByteSize) Format(f fmt.State, verb rune) {
 		prec = 0
 	}
 
-	output := formatByteSize(nBytes, prec)
+	var output []byte
+	if f.Flag('+') {
+		output = formatByteSizeSI(nBytes, prec, defaultRoundingMode)
+	} else {
+		output = formatByteSize(nBytes, prec, defaultRoundingMode)
+	}
 
 	width, haveWidth := f.Width()
 	if !haveWidth {
@@ -141,13 +223,371 @@ func equivalentFormat(f fmt.State, verb rune) string {
 	return formatString + string(verb)
 }
 
+// The Scan method makes *ByteSize satisfy the fmt.Scanner interface, so that
+// fmt.Sscanf("3.45MiB", "%v", &bs) and friends can parse the strings produced
+// by Format/String.
+//
+// It accepts an optional leading sign, a decimal number (integer, or with a
+// single '.' and a fractional part), optionally followed by a single space
+// and then a unit suffix.  The suffix may be omitted (meaning bytes), or may
+// be any of the case-insensitive forms that formatByteSize can produce: "B",
+// or one of "K","M","G","T","P","E" optionally followed by "B" or "iB".
+//
+// The verb is ignored: %v, %d and %s all parse the same way.
+//
+func (b *ByteSize) Scan(state fmt.ScanState, verb rune) error {
+	state.SkipSpace()
+	var buf []byte
+	r, _, err := state.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r == '+' || r == '-' {
+		buf = append(buf, byte(r))
+		if r, _, err = state.ReadRune(); err != nil {
+			return err
+		}
+	}
+	sawDigit := false
+	for (r >= '0' && r <= '9') || r == '.' {
+		if r != '.' {
+			sawDigit = true
+		}
+		buf = append(buf, byte(r))
+		if r, _, err = state.ReadRune(); err != nil {
+			break
+		}
+	}
+	if !sawDigit {
+		return fmt.Errorf("bytesize: Scan: no digits found")
+	}
+	if err == nil && r == ' ' {
+		r, _, err = state.ReadRune()
+	}
+	for err == nil && ((r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')) {
+		buf = append(buf, byte(r))
+		if r, _, err = state.ReadRune(); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		state.UnreadRune()
+	}
+	v, err := parseByteSize(string(buf))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// parseByteSize parses s, which must look like something formatByteSize could
+// have produced: an optional sign, a decimal number (integer, or with a
+// single '.' and a fractional part), optional whitespace, and an optional
+// case-insensitive unit suffix ("", "B", "K", "KB", "KiB", ..., "E", "EB",
+// "EiB"). ParseByteSize and Scan both build on this.
+//
+// The numeric part is scaled by 1024^level, where level is determined by the
+// suffix, using integer arithmetic throughout (int64, and 128-bit unsigned
+// for the fractional part); a *strconv.NumError wrapping
+// strconv.ErrRange is returned if the result would overflow int64.
+//
+func parseByteSize(s string) (ByteSize, error) {
+	orig := s
+	if s == "" {
+		return 0, fmt.Errorf("bytesize: cannot parse an empty string")
+	}
+	neg := false
+	if s[0] == '+' || s[0] == '-' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	i := 0
+	for i < len(s) && ((s[i] >= '0' && s[i] <= '9') || s[i] == '.') {
+		i++
+	}
+	numPart, suffixPart := s[:i], strings.TrimLeft(s[i:], " \t\n\v\f\r")
+	if numPart == "" || numPart == "." {
+		return 0, fmt.Errorf("bytesize: %q is not a valid size: missing number", orig)
+	}
+	if strings.Count(numPart, ".") > 1 {
+		return 0, fmt.Errorf("bytesize: %q is not a valid size: too many decimal points", orig)
+	}
+	level, err := parseSizeSuffix(suffixPart)
+	if err != nil {
+		return 0, fmt.Errorf("bytesize: %q is not a valid size: %v", orig, err)
+	}
+
+	intPart, fracPart := numPart, ""
+	if dot := strings.IndexByte(numPart, '.'); dot >= 0 {
+		intPart, fracPart = numPart[:dot], numPart[dot+1:]
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	mantissa, err := strconv.ParseInt(intPart, 10, 64)
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return 0, rangeError(orig)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("bytesize: %q is not a valid size: %v", orig, err)
+	}
+
+	const maxInt64 = int64(1<<63 - 1)
+	unitSize := int64(1)
+	for k := uint(0); k < level; k++ {
+		if unitSize > maxInt64/1024 {
+			return 0, rangeError(orig)
+		}
+		unitSize *= 1024
+	}
+	if mantissa != 0 && unitSize != 1 && mantissa > maxInt64/unitSize {
+		return 0, rangeError(orig)
+	}
+	value := mantissa * unitSize
+
+	if fracPart != "" {
+		if len(fracPart) > 18 {
+			fracPart = fracPart[:18] // more digits than any int64 needs
+		}
+		fracDigits, err := strconv.ParseInt(fracPart, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bytesize: %q is not a valid size: %v", orig, err)
+		}
+		scale := uint64(1)
+		for range fracPart {
+			scale *= 10
+		}
+		// fracDigits*unitSize can easily overflow 64 bits (e.g. "0.999999EiB"),
+		// so do the multiply-then-divide at 128-bit precision.
+		hi, lo := bits.Mul64(uint64(fracDigits), uint64(unitSize))
+		var carry uint64
+		lo, carry = bits.Add64(lo, scale/2, 0)
+		hi += carry
+		if hi >= scale {
+			return 0, rangeError(orig)
+		}
+		frac, _ := bits.Div64(hi, lo, scale)
+		if frac > uint64(maxInt64-value) {
+			return 0, rangeError(orig)
+		}
+		value += int64(frac)
+	}
+
+	if value < 0 {
+		return 0, rangeError(orig)
+	}
+	if neg {
+		value = -value
+	}
+	return ByteSize(value), nil
+}
+
+// rangeError reports that orig named a magnitude too large (or too close to
+// the extreme of a fractional rounding) to fit in an int64 ByteSize, in the
+// same form strconv's own parsers use for overflow.
+func rangeError(orig string) error {
+	return &strconv.NumError{Func: "ParseByteSize", Num: orig, Err: strconv.ErrRange}
+}
+
+// ParseByteSize parses s as a ByteSize: an optional sign, a decimal number
+// (integer, or with a single '.' and a fractional part), optional
+// whitespace, and an optional case-insensitive unit suffix ("", "B", "K",
+// "KB", "KiB", ..., "E", "EB", "EiB"), which covers the suffixes String/Format
+// print at any precision. Suffixes are always read as powers of 1024, so the
+// SI forms printed by "%+v" and FormatSI ("1.50kB") parse as binary
+// multiples. It also accepts the GoString form, "ByteSize(123)" or
+// "ByteSize(-123)", which round-trips exactly; a %v string round-trips
+// exactly only for exact multiples of its unit, since other values print a
+// rounded mantissa.
+//
+// ParseByteSize returns a *strconv.NumError wrapping strconv.ErrRange if the
+// value would overflow int64. That includes a rounded string such as the
+// "8.00EiB" that String prints for values just below 8EiB.
+func ParseByteSize(s string) (ByteSize, error) {
+	if rest := strings.TrimSuffix(strings.TrimPrefix(s, "ByteSize("), ")"); rest != s {
+		n, err := strconv.ParseInt(rest, 10, 64)
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return 0, rangeError(s)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("bytesize: %q is not a valid size: %v", s, err)
+		}
+		return ByteSize(n), nil
+	}
+	return parseByteSize(s)
+}
+
+// Set parses s with ParseByteSize and stores the result in *b, so that
+// ByteSize satisfies the flag.Value interface — e.g.
+//	var maxSize bytesize.ByteSize
+//	flag.Var(&maxSize, "max", "maximum allowed size")
+func (b *ByteSize) Set(s string) error {
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// parseSizeSuffix returns the power-of-1024 level (0 for bytes, 1 for
+// Ki/kilo, ..., 6 for Ei/exa) denoted by a case-insensitive unit suffix, as
+// produced by formatByteSize: "", "B", "K", "KB", "KiB", ..., "E", "EB", "EiB".
+func parseSizeSuffix(suffix string) (uint, error) {
+	if suffix == "" {
+		return 0, nil
+	}
+	upper := strings.ToUpper(suffix)
+	if upper == "B" {
+		return 0, nil
+	}
+	level := strings.IndexByte(binaryPrefixLetters, upper[0])
+	if level <= 0 {
+		return 0, fmt.Errorf("unrecognised unit %q", suffix)
+	}
+	switch upper[1:] {
+	case "", "B", "IB":
+		return uint(level), nil
+	}
+	return 0, fmt.Errorf("unrecognised unit %q", suffix)
+}
+
+// siPrefixLetters[level] gives the SI (decimal) prefix letter for a
+// power-of-1000 level, running from level 0 ("no suffix") up to level 6
+// (E, exa).  Note that, unlike binaryPrefixLetters, level 1's letter is
+// lower-case "k", per SI convention.
+const siPrefixLetters = "!kMGTPE"
+
+// FormatSI formats b as a decimal number with 1 to 4 significant digits,
+// followed by an SI (base-1000) suffix: "B", "kB", "MB", "GB", "TB", "PB" or
+// "EB" as appropriate.  prec selects the suffix form exactly as the
+// precision of a "%v" format verb would (see the package documentation),
+// except that .2 and .3 both give the "kB"-style forms; a prec above 3 is
+// treated as 3, and a negative one as 0.  It rounds using
+// the current default RoundingMode; use FormatSIWith to choose one
+// explicitly.
+//
+// FormatSI is the base-1000 equivalent of String/"%v"; use "%+v" to get the
+// same output via fmt.
+//
+func (b ByteSize) FormatSI(prec int) string {
+	return b.FormatSIWith(prec, defaultRoundingMode)
+}
+
+// FormatSIWith is FormatSI with an explicit RoundingMode rather than the
+// current default one.
+func (b ByteSize) FormatSIWith(prec int, mode RoundingMode) string {
+	if prec > 3 {
+		prec = 3
+	} else if prec < 0 {
+		prec = 0
+	}
+	return string(formatByteSizeSI(int64(b), prec, mode))
+}
+
+// divModRound returns round(value*mul/divisor) according to mode. value,
+// mul and divisor must all be non-negative; neg carries the sign of the
+// original (unrounded, possibly negative) number being formatted, which
+// RoundCeiling and RoundFloor need in order to round asymmetrically.
+//
+// The multiply-then-divide happens at 128-bit precision (via math/bits),
+// so this can't overflow int64 even when value*mul would.
+func divModRound(value, mul, divisor int64, neg bool, mode RoundingMode) int64 {
+	hi, lo := bits.Mul64(uint64(value), uint64(mul))
+	q, r := bits.Div64(hi, lo, uint64(divisor))
+	if r == 0 {
+		return int64(q)
+	}
+	d := uint64(divisor)
+	switch mode {
+	case RoundDown:
+		// q is already value*mul/divisor truncated toward zero.
+	case RoundUp:
+		q++
+	case RoundCeiling:
+		if !neg {
+			q++
+		}
+	case RoundFloor:
+		if neg {
+			q++
+		}
+	case RoundHalfEven:
+		if 2*r > d || (2*r == d && q%2 != 0) {
+			q++
+		}
+	default: // RoundHalfUp
+		if 2*r >= d {
+			q++
+		}
+	}
+	return int64(q)
+}
+
+// formatByteSizeSI is the SI (base-1000) counterpart of formatByteSize.
+func formatByteSizeSI(value int64, prec int, mode RoundingMode) []byte {
+	ret := make([]byte, 0, 32)
+	neg := value < 0
+	if neg {
+		ret = append(ret, '-')
+		value = -value
+	}
+	if value < 1000 {
+		ret = appendDecimal(ret, value)
+		if prec != 0 {
+			ret = append(ret, 'B')
+		}
+		return ret
+	}
+	level := 1
+	unitSize := int64(1000)
+	for level < 6 && value >= unitSize*1000 {
+		unitSize *= 1000
+		level++
+	}
+	// Now 1 <= level <= 6 and unitSize <= value.
+
+	if value%unitSize == 0 {
+		ret = appendDecimal(ret, value/unitSize)
+	} else {
+		// Round in the last digit per mode, choosing 0, 1 or 2 digits after the
+		// point depending on how close value is to the next power of 1000 —
+		// computed via the quotient/remainder of value÷unitSize so that
+		// nothing here can overflow int64, however large unitSize gets.
+		q, r := value/unitSize, value%unitSize
+		digitsAfterPoint, mul := 0, int64(1)
+		if q < 9 || (q == 9 && r < unitSize-unitSize/200) {
+			digitsAfterPoint, mul = 2, 100
+		} else if q < 99 || (q == 99 && r < unitSize-unitSize/20) {
+			digitsAfterPoint, mul = 1, 10
+		}
+		ret = appendDecimal(ret, divModRound(value, mul, unitSize, neg, mode))
+		// Maybe insert a decimal point.
+		n := len(ret)
+		switch digitsAfterPoint {
+		case 2:
+			ret = append(ret[:n-2], '.', ret[n-2], ret[n-1])
+		case 1:
+			ret = append(ret[:n-1], '.', ret[n-1])
+		}
+	}
+	letter := siPrefixLetters[level]
+	switch prec {
+	case 0, 1:
+		ret = append(ret, letter)
+	default:
+		ret = append(ret, letter, 'B')
+	}
+	return ret
+}
+
 // formatByteSize does the hard work for this package.
-func formatByteSize(value int64, prec int) []byte {
-	const suffix1 = "!KMGTPE"
-	const _1 = int64(1)
+func formatByteSize(value int64, prec int, mode RoundingMode) []byte {
+	const suffix1 = binaryPrefixLetters
 	ret := make([]byte, 0, 32) // Plenty of room.
-	letter := byte(0)
-	if value < 0 {
+	neg := value < 0
+	if neg {
 		ret = append(ret, '-')
 		value = -value
 	}
@@ -159,53 +599,37 @@ func formatByteSize(value int64, prec int) []byte {
 		}
 		return ret
 	}
-	if value >= (1 << 60) {
-		// This is a simple way to avoid integer overflows
-		const unitSize = 1 << 60
-		if (value & (unitSize - 1)) == 0 {
-			// Exact multiples of unitSize are a special case.
-			ret = appendDecimal(ret, value>>60)
-		} else {
-			ret = appendDecimal(ret, ((value>>50)*100+512)>>10)
-			//D// hook1("EiB, decimals==2, ret=%q", ret)
-			n := len(ret)
-			ret = append(ret[:n-2], '.', ret[n-2], ret[n-1])
-		}
-		letter = 'E'
+	level := sizeLevel(value)
+	unitSize := int64(1) << (10 * level) // 2**10 or 2**20 or ... or 2**60
+	// Now 1 <= level <= 6 and unitSize <= value <= 1023*unitSize
+
+	// Format value÷unitSize in decimal with 1-4 digits.
+	if (value & (unitSize - 1)) == 0 {
+		// Exact multiples of unitSize are a special case.
+		ret = appendDecimal(ret, value>>(10*level))
 	} else {
-		level := uint(1)
-		for k := (_1 << 20); value >= k && k < (_1<<60); k <<= 10 {
-			level++
+		// For other values, round in the last digit per mode.  digitsAfterPoint
+		// is chosen from the quotient/remainder of value÷unitSize, rather than
+		// comparing value against 10*unitSize or 100*unitSize directly, so that
+		// nothing here can overflow int64 even at the EiB level.
+		q, r := value>>(10*level), value&(unitSize-1)
+		digitsAfterPoint, mul := 0, int64(1)
+		if q < 9 || (q == 9 && r < unitSize-unitSize/200) {
+			digitsAfterPoint, mul = 2, 100
+		} else if q < 99 || (q == 99 && r < unitSize-unitSize/20) {
+			digitsAfterPoint, mul = 1, 10
+		}
+		ret = appendDecimal(ret, divModRound(value, mul, unitSize, neg, mode))
+		// Maybe insert a decimal point.
+		n := len(ret)
+		switch digitsAfterPoint {
+		case 2:
+			ret = append(ret[:n-2], '.', ret[n-2], ret[n-1])
+		case 1:
+			ret = append(ret[:n-1], '.', ret[n-1])
 		}
-		unitSize := int64(1 << (10 * level)) // 2**10 or 2**20 or ... or 2**60
-		// Now 1 <= level <= 6 and unitSize <= value <= 1023*unitSize
-
-		// Format value÷unitSize in decimal with 1-4 digits.
-		if (value & (unitSize - 1)) == 0 {
-			// Exact multiples of unitSize are a special case.
-			ret = appendDecimal(ret, value>>(10*level))
-		} else {
-			// For other values, round up in the last digit.
-			digitsAfterPoint, v := 0, value
-			if value < 10*unitSize-unitSize/200 {
-				digitsAfterPoint = 2
-				v = 100 * value
-			} else if value < 100*unitSize-unitSize/20 {
-				digitsAfterPoint = 1
-				v = 10 * value
-			}
-			ret = appendDecimal(ret, (v+unitSize/2)>>(10*level))
-			// Maybe insert a decimal point.
-			n := len(ret)
-			switch digitsAfterPoint {
-			case 2:
-				ret = append(ret[:n-2], '.', ret[n-2], ret[n-1])
-			case 1:
-				ret = append(ret[:n-1], '.', ret[n-1])
-			}
-		}
-		letter = suffix1[level]
 	}
+	letter := suffix1[level]
 	switch prec {
 	case 0, 1:
 		ret = append(ret, letter)
@@ -217,6 +641,78 @@ func formatByteSize(value int64, prec int) []byte {
 	return ret
 }
 
+// Split returns the decimal mantissa that String() would print for b — e.g.
+// "23.4" or "-1" or "999" — and the power-of-1024 level (0 for bytes, 1 for
+// Ki, ..., 6 for Ei) that determines which unit suffix belongs after it,
+// without the suffix itself. As with Format, prec selects only the suffix
+// form, so the mantissa is the same for every prec; a prec above 3 is
+// treated as 3, and a negative one as 0.
+//
+// Split exists so that other packages can build their own presentation of a
+// ByteSize — translated or otherwise reformatted unit suffixes, a different
+// decimal separator, locale-specific digit grouping — on top of the same
+// rounding rules as String, without duplicating formatByteSize's rounding
+// logic. See bytesize/i18n for an example.
+//
+// Split rounds using the current default RoundingMode, exactly as String
+// and Format's %v/%s verbs do.
+//
+func (b ByteSize) Split(prec int) (mantissa string, level int) {
+	if prec > 3 {
+		prec = 3
+	} else if prec < 0 {
+		prec = 0
+	}
+	level = int(sizeLevel(int64(b)))
+	full := formatByteSize(int64(b), prec, defaultRoundingMode)
+	suffixLen := 0
+	switch {
+	case level == 0 && prec == 0:
+		suffixLen = 0
+	case level == 0:
+		suffixLen = 1 // "B"
+	case prec == 0 || prec == 1:
+		suffixLen = 1 // bare letter, e.g. "K"
+	case prec == 2:
+		suffixLen = 2 // e.g. "KB"
+	default:
+		suffixLen = 3 // e.g. "KiB"
+	}
+	return string(full[:len(full)-suffixLen]), level
+}
+
+// sizeLevel returns the power-of-1024 bucket formatByteSize would put value
+// in: 0 for |value|<1024, 1..6 for Ki..Ei.
+func sizeLevel(value int64) uint {
+	if value < 0 {
+		value = -value
+	}
+	if value < 1024 {
+		return 0
+	}
+	if value >= 1<<60 {
+		return 6
+	}
+	level := uint(1)
+	for k := int64(1) << 20; value >= k && k < (1 << 60); k <<= 10 {
+		level++
+	}
+	return level
+}
+
+// BinaryPrefixLetter returns the IEC binary-prefix letter for a
+// power-of-1024 level, as returned by Split: 'K' for 1, 'M' for 2, and so on
+// up to 'E' for 6. Level 0 (bytes) has no prefix letter, and returns 0. It
+// exists for other packages that build their own presentation on top of
+// Split, such as bytesize/i18n.
+//
+func BinaryPrefixLetter(level int) byte {
+	if level <= 0 || level >= len(binaryPrefixLetters) {
+		return 0
+	}
+	return binaryPrefixLetters[level]
+}
+
 // appendDecimal() appends the decimal form of an int64 value, which MUST be
 // positive, to a byte slice.
 //
@@ -5,7 +5,10 @@
 package bytesize
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"strconv"
 	"testing"
 )
 
@@ -134,6 +137,305 @@ func tryFormat(t *testing.T, format string, v ByteSize, expected string) {
 	}
 }
 
+// isExactMultiple reports whether num is an exact multiple of its own
+// formatting unit (1 for level 0, 1024 for KiB, ..., 1024^6 for EiB), i.e.
+// whether String()/Format print it with no rounding at all. Only such values
+// have a stable round trip through Scan/ParseByteSize: anything else is
+// printed as a rounded mantissa, which can parse back to a different (but
+// equally validly-rounded) value — e.g. "1.00KiB" parses to exactly 1KiB,
+// which reformats as the shorter "1KiB", not "1.00KiB".
+func isExactMultiple(num int64) bool {
+	if num < 0 {
+		num = -num
+	}
+	unitSize := int64(1) << (10 * sizeLevel(num))
+	return num%unitSize == 0
+}
+
+// TestScanRoundTrip checks that, for every exact-multiple case in
+// tryEveryCase, parsing the output of String() with fmt.Sscanf("%v", …) and
+// formatting the result again reproduces the same string. (Non-exact
+// multiples are necessarily rounded when formatted, so re-parsing them
+// doesn't reliably reproduce the original string — see isExactMultiple.)
+func TestScanRoundTrip(t *testing.T) {
+	tryEveryCase(t, func(t *testing.T, num int64, numstr string, code metricPrefix) {
+		if !isExactMultiple(num) {
+			return
+		}
+		s := ByteSize(num).String()
+		var v ByteSize
+		if n, err := fmt.Sscanf(s, "%v", &v); err != nil || n != 1 {
+			t.Errorf("Sscanf(%q) failed: n=%d, err=%v", s, n, err)
+			return
+		}
+		if got := v.String(); got != s {
+			t.Errorf("Sscanf(%q) => %d, which formats as %q, wanted %q",
+				s, int64(v), got, s)
+		}
+	})
+}
+
+func TestScanCases(t *testing.T) {
+	cases := []struct {
+		input string
+		want  ByteSize
+	}{
+		{"1023B", 1023},
+		{"-1025", -1025},
+		{"0", 0},
+		{"1K", 1024},
+		{"1KB", 1024},
+		{"1KiB", 1024},
+		{"1kib", 1024},
+		{"-1023", -1023},
+		{" 42MiB", 42 << 20},
+	}
+	for _, c := range cases {
+		var v ByteSize
+		n, err := fmt.Sscanf(c.input, "%v", &v)
+		if err != nil || n != 1 {
+			t.Errorf("Sscanf(%q) failed: n=%d, err=%v", c.input, n, err)
+			continue
+		}
+		if v != c.want {
+			t.Errorf("Sscanf(%q) => %d, wanted %d", c.input, int64(v), int64(c.want))
+		}
+	}
+}
+
+// TestScanFractionalEiB checks parsing right at the top metric prefix, where
+// the scaling arithmetic is most likely to overflow 64 bits.
+func TestScanFractionalEiB(t *testing.T) {
+	var v ByteSize
+	if _, err := fmt.Sscanf("1.23EiB", "%v", &v); err != nil {
+		t.Fatalf("Sscanf(%q) failed: %v", "1.23EiB", err)
+	}
+	if got := v.String(); got != "1.23EiB" {
+		t.Errorf("Sscanf(%q) => %d, which formats as %q, wanted %q",
+			"1.23EiB", int64(v), got, "1.23EiB")
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	cases := []string{"", "abc", "KiB", "1XB", "1.2.3KiB", "9EiB", "-9EiB"}
+	for _, s := range cases {
+		var v ByteSize
+		if _, err := fmt.Sscanf(s, "%v", &v); err == nil {
+			t.Errorf("Sscanf(%q) => %d, wanted an error", s, int64(v))
+		}
+	}
+}
+
+func TestParseByteSizeSuffixes(t *testing.T) {
+	cases := []struct {
+		input string
+		want  ByteSize
+	}{
+		{"0", 0}, {"1023B", 1023}, {"1023", 1023},
+		{"1K", 1024}, {"1KB", 1024}, {"1KiB", 1024}, {"1kib", 1024},
+		{"1M", 1 << 20}, {"1MB", 1 << 20}, {"1MiB", 1 << 20},
+		{"1G", 1 << 30}, {"1GB", 1 << 30}, {"1GiB", 1 << 30},
+		{"1T", 1 << 40}, {"1TB", 1 << 40}, {"1TiB", 1 << 40},
+		{"1P", 1 << 50}, {"1PB", 1 << 50}, {"1PiB", 1 << 50},
+		{"1E", 1 << 60}, {"1EB", 1 << 60}, {"1EiB", 1 << 60},
+		{"1.5G", 1536 << 20}, {"2KiB", 2048}, {"1 KiB", 1024},
+		{"-1025", -1025}, {"+42", 42}, {"42MiB", 42 << 20},
+		{"ByteSize(123)", 123}, {"ByteSize(-123)", -123},
+	}
+	for _, c := range cases {
+		got, err := ParseByteSize(c.input)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q) failed: %v", c.input, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseByteSize(%q) => %d, wanted %d", c.input, int64(got), int64(c.want))
+		}
+	}
+}
+
+func TestParseByteSizeErrors(t *testing.T) {
+	cases := []string{"", "abc", "KiB", "1XB", "1.2.3KiB", "ByteSize(abc)"}
+	for _, s := range cases {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Errorf("ParseByteSize(%q) => nil error, wanted one", s)
+		}
+	}
+}
+
+// TestParseByteSizeOverflow checks that values right at, and just past, the
+// EiB boundary are rejected with a *strconv.NumError wrapping
+// strconv.ErrRange, rather than silently wrapping around.
+func TestParseByteSizeOverflow(t *testing.T) {
+	cases := []string{"9EiB", "-9EiB", "8EiB", "8192PiB"}
+	for _, s := range cases {
+		_, err := ParseByteSize(s)
+		if err == nil {
+			t.Errorf("ParseByteSize(%q) => nil error, wanted strconv.ErrRange", s)
+			continue
+		}
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("ParseByteSize(%q) => %v, wanted an error wrapping strconv.ErrRange", s, err)
+		}
+	}
+	if _, err := ParseByteSize("7EiB"); err != nil {
+		t.Errorf("ParseByteSize(%q) failed: %v", "7EiB", err)
+	}
+}
+
+// TestParseByteSizeRoundTrip checks that, for every exact-multiple value
+// tryEveryCase exercises (see isExactMultiple) and every precision
+// String/Format support, formatting then parsing then reformatting
+// reproduces the same string — the same property TestScanRoundTrip checks
+// for Scan. Non-exact multiples are skipped: their mantissa is rounded, so
+// neither the reformatted string nor even a successful parse is guaranteed
+// (e.g. 8191<<50 formats at .0 precision as "8E", which is one past the
+// largest representable EiB count and so correctly fails to parse).
+func TestParseByteSizeRoundTrip(t *testing.T) {
+	tryEveryCase(t, func(t *testing.T, num int64, numstr string, code metricPrefix) {
+		if !isExactMultiple(num) {
+			return
+		}
+		for _, prec := range []int{0, 1, 2, 3} {
+			s := fmt.Sprintf("%.*v", prec, ByteSize(num))
+			got, err := ParseByteSize(s)
+			if err != nil {
+				t.Errorf("ParseByteSize(%q) (from %%.%dv of %d) failed: %v", s, prec, num, err)
+				continue
+			}
+			if again := fmt.Sprintf("%.*v", prec, got); again != s {
+				t.Errorf("ParseByteSize(%q) => %d, which formats as %q, wanted %q",
+					s, int64(got), again, s)
+			}
+		}
+	})
+}
+
+func TestByteSizeFlagValue(t *testing.T) {
+	var size ByteSize
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&size, "max", "maximum allowed size")
+	if err := fs.Parse([]string{"-max=1.5GiB"}); err != nil {
+		t.Fatalf("fs.Parse failed: %v", err)
+	}
+	if want := ByteSize(1536 << 20); size != want {
+		t.Errorf("after -max=1.5GiB, size = %d, wanted %d", int64(size), int64(want))
+	}
+	if err := fs.Parse([]string{"-max=bogus"}); err == nil {
+		t.Errorf("fs.Parse([-max=bogus]) => nil error, wanted one")
+	}
+}
+
+// TestRoundingModesAtHalfway exercises every RoundingMode at values whose
+// remainder against the rounding divisor is exactly half, both at 2-digit
+// precision (1152, i.e. "1.125KiB" before rounding) and at 1-digit precision
+// (10496, i.e. "10.25KiB" before rounding), and checks that negating the
+// value rounds symmetrically for HalfUp/HalfEven/Down/Up but asymmetrically
+// for Ceiling/Floor.
+func TestRoundingModesAtHalfway(t *testing.T) {
+	cases := []struct {
+		mode        RoundingMode
+		want2digit  string
+		want2digitN string // FormatWith(-1152, ...)
+		want1digit  string
+		want1digitN string // FormatWith(-10496, ...)
+	}{
+		{RoundHalfUp, "1.13KiB", "-1.13KiB", "10.3KiB", "-10.3KiB"},
+		{RoundHalfEven, "1.12KiB", "-1.12KiB", "10.2KiB", "-10.2KiB"},
+		{RoundDown, "1.12KiB", "-1.12KiB", "10.2KiB", "-10.2KiB"},
+		{RoundUp, "1.13KiB", "-1.13KiB", "10.3KiB", "-10.3KiB"},
+		{RoundCeiling, "1.13KiB", "-1.12KiB", "10.3KiB", "-10.2KiB"},
+		{RoundFloor, "1.12KiB", "-1.13KiB", "10.2KiB", "-10.3KiB"},
+	}
+	for _, c := range cases {
+		if got := ByteSize(1152).FormatWith(3, c.mode); got != c.want2digit {
+			t.Errorf("ByteSize(1152).FormatWith(3, %v) => %q, wanted %q", c.mode, got, c.want2digit)
+		}
+		if got := ByteSize(-1152).FormatWith(3, c.mode); got != c.want2digitN {
+			t.Errorf("ByteSize(-1152).FormatWith(3, %v) => %q, wanted %q", c.mode, got, c.want2digitN)
+		}
+		if got := ByteSize(10496).FormatWith(3, c.mode); got != c.want1digit {
+			t.Errorf("ByteSize(10496).FormatWith(3, %v) => %q, wanted %q", c.mode, got, c.want1digit)
+		}
+		if got := ByteSize(-10496).FormatWith(3, c.mode); got != c.want1digitN {
+			t.Errorf("ByteSize(-10496).FormatWith(3, %v) => %q, wanted %q", c.mode, got, c.want1digitN)
+		}
+	}
+}
+
+func TestFormatWithMatchesStringByDefault(t *testing.T) {
+	tryEveryCase(t, func(t *testing.T, num int64, numstr string, code metricPrefix) {
+		v := ByteSize(num)
+		if got := v.FormatWith(3, RoundHalfUp); got != v.String() {
+			t.Errorf("ByteSize(%d).FormatWith(3, RoundHalfUp) => %q, wanted %q (== String())",
+				num, got, v.String())
+		}
+	})
+}
+
+func TestSetDefaultRoundingMode(t *testing.T) {
+	SetDefaultRoundingMode(RoundDown)
+	defer SetDefaultRoundingMode(RoundHalfUp)
+	if got := ByteSize(1152).String(); got != "1.12KiB" {
+		t.Errorf("with default mode RoundDown, ByteSize(1152).String() => %q, wanted %q", got, "1.12KiB")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		num          int64
+		prec         int
+		wantMantissa string
+		wantLevel    int
+	}{
+		{999, 3, "999", 0},
+		{-1, 3, "-1", 0},
+		{1256 << 50, 3, "1.23", 6},
+		{1024, 3, "1", 1},
+		{10235, 3, "10.0", 1},
+	}
+	for _, c := range cases {
+		mantissa, level := ByteSize(c.num).Split(c.prec)
+		if mantissa != c.wantMantissa || level != c.wantLevel {
+			t.Errorf("ByteSize(%d).Split(%d) => (%q, %d), wanted (%q, %d)",
+				c.num, c.prec, mantissa, level, c.wantMantissa, c.wantLevel)
+		}
+	}
+}
+
+func TestFormatSI(t *testing.T) {
+	cases := []struct {
+		num  int64
+		prec int
+		want string
+	}{
+		{999, 3, "999B"},
+		{999, 0, "999"},
+		{1000, 3, "1kB"},
+		{1000, 2, "1kB"},
+		{1000, 1, "1k"},
+		{1000, 0, "1k"},
+		{1500, 3, "1.50kB"},
+		{-1500, 3, "-1.50kB"},
+		{999500, 3, "1000kB"},  // rounds up, but stays at the "k" level
+		{999499, 3, "999kB"},
+		{1000000, 3, "1MB"},
+		{1 << 62, 3, "4.61EB"},
+	}
+	for _, c := range cases {
+		got := ByteSize(c.num).FormatSI(c.prec)
+		if got != c.want {
+			t.Errorf("ByteSize(%d).FormatSI(%d) => %q, wanted %q",
+				c.num, c.prec, got, c.want)
+		}
+		formatVerb := fmt.Sprintf("%%+.%dv", c.prec)
+		if got := fmt.Sprintf(formatVerb, ByteSize(c.num)); got != c.want {
+			t.Errorf("Sprintf(%q, %d) => %q, wanted %q",
+				formatVerb, c.num, got, c.want)
+		}
+	}
+}
+
 /*
 ;;; Emacs lisp code to nicely format tryEveryCase() code
 (defun fix-bytesize-test-code () (interactive "*")
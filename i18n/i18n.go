@@ -0,0 +1,133 @@
+// Package i18n adds locale-aware formatting for bytesize.ByteSize values,
+// built on golang.org/x/text.
+//
+// It deliberately lives in its own package, separate from bytesize itself,
+// so that importing bytesize never pulls in golang.org/x/text: only a
+// program that imports bytesize/i18n pays that cost.
+//
+// FormatLocalized and Formatter print the same mantissa and significant
+// digits that bytesize.ByteSize.String()/Format would, but render the
+// decimal separator, digit grouping and unit suffix according to a
+// language.Tag: a French reader sees "1,23 Mio", a German reader sees
+// "1,23 MiB" (with a non-breaking space), and so on.  They get the
+// mantissa itself from (bytesize.ByteSize).Split, so the rounding behaviour
+// always matches the core package exactly; only its presentation differs.
+//
+package i18n
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	"github.com/c12h/bytesize"
+)
+
+// localeUnits holds what FormatLocalized needs to render a ByteSize's unit
+// suffix in one locale: the local word for "byte" (most languages use "B",
+// French uses "o" for "octet"), and the separator placed between the number
+// and the suffix.
+type localeUnits struct {
+	byteWord string
+	sep      string
+}
+
+const nbsp = " "
+
+// unitTable maps a base language (as returned by language.Tag.Base()) to its
+// localeUnits.  English is used for any language not listed here;
+// contributors adding a new locale only need to add an entry.
+//
+// The binary-prefix letters themselves ("K", "M", ..., "E") and the IEC "i"
+// marker are not translated: both are international symbols, used as-is in
+// every locale below.
+var unitTable = map[string]localeUnits{
+	"en": {byteWord: "B", sep: ""},
+	"fr": {byteWord: "o", sep: " "},
+	"de": {byteWord: "B", sep: nbsp},
+	"hi": {byteWord: "B", sep: " "},
+}
+
+// FormatLocalized renders b the way bytesize.ByteSize.Format would — 1 to 4
+// significant digits followed by a unit suffix, with prec selecting the
+// suffix form exactly as it would for Format — but with the decimal
+// separator, digit grouping and unit name localized for tag. p supplies the
+// number-formatting conventions (via golang.org/x/text/message) and must
+// have been created for the same tag, e.g. with message.NewPrinter(tag).
+func FormatLocalized(b bytesize.ByteSize, tag language.Tag, p *message.Printer, prec int) string {
+	return formatLocalized(b, p, tag, prec)
+}
+
+// Formatter returns a function that formats ByteSize values for tag, using
+// the default suffix form (equivalent to precision 3, i.e. "%v"). It is a
+// convenience for callers who format many values for one fixed locale, e.g.
+// when rendering a table for a single user.
+func Formatter(tag language.Tag) func(bytesize.ByteSize) string {
+	p := message.NewPrinter(tag)
+	return func(b bytesize.ByteSize) string {
+		return formatLocalized(b, p, tag, 3)
+	}
+}
+
+func formatLocalized(b bytesize.ByteSize, p *message.Printer, tag language.Tag, prec int) string {
+	if prec > 3 || prec < 0 {
+		prec = 3
+	}
+	mantissa, level := b.Split(prec)
+	base, _ := tag.Base()
+	units, ok := unitTable[base.String()]
+	if !ok {
+		units = unitTable["en"]
+	}
+
+	neg := strings.HasPrefix(mantissa, "-")
+	if neg {
+		mantissa = mantissa[1:]
+	}
+	fracDigits := 0
+	if dot := strings.IndexByte(mantissa, '.'); dot >= 0 {
+		fracDigits = len(mantissa) - dot - 1
+	}
+	value, err := strconv.ParseFloat(mantissa, 64)
+	if err != nil {
+		// Split only ever produces well-formed decimals, so this would be a
+		// bug in this package rather than bad input.
+		panic("bytesize/i18n: malformed mantissa " + strconv.Quote(mantissa))
+	}
+	if neg {
+		value = -value
+	}
+
+	formattedNumber := p.Sprint(number.Decimal(value,
+		number.MinFractionDigits(fracDigits), number.MaxFractionDigits(fracDigits)))
+
+	suffix := localizedSuffix(units, level, prec)
+	if suffix == "" {
+		return formattedNumber
+	}
+	return formattedNumber + units.sep + suffix
+}
+
+// localizedSuffix builds the unit suffix for a level/prec combination the
+// same way formatByteSize's switch on prec does, substituting units.byteWord
+// for the English "B".
+func localizedSuffix(units localeUnits, level int, prec int) string {
+	if level == 0 {
+		if prec == 0 {
+			return ""
+		}
+		return units.byteWord
+	}
+	letter := string(bytesize.BinaryPrefixLetter(level))
+	switch prec {
+	case 0, 1:
+		return letter
+	case 2:
+		return letter + units.byteWord
+	default:
+		return letter + "i" + units.byteWord
+	}
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/c12h/bytesize"
+)
+
+func TestFormatLocalized(t *testing.T) {
+	// 1.23 MiB, one way or another, in each locale.
+	cases := []struct {
+		tag  language.Tag
+		want string
+	}{
+		{language.English, "1.23MiB"},
+		{language.French, "1,23 Mio"},
+		{language.German, "1,23" + nbsp + "MiB"},
+		{language.MustParse("hi-IN"), "1.23 MiB"}, // hi-IN uses "." like en, not ","
+	}
+	const num = 1256 << 10
+	for _, c := range cases {
+		p := message.NewPrinter(c.tag)
+		got := FormatLocalized(bytesize.ByteSize(num), c.tag, p, 3)
+		if got != c.want {
+			t.Errorf("FormatLocalized(%d, %v) => %q, wanted %q", num, c.tag, got, c.want)
+		}
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	format := Formatter(language.German)
+	got := format(bytesize.ByteSize(1256 << 10))
+	want := "1,23" + nbsp + "MiB"
+	if got != want {
+		t.Errorf("Formatter(German)(1256<<10) => %q, wanted %q", got, want)
+	}
+}
+
+// TestFormatLocalizedBelowOneK exercises the unsuffixed-byte-count path
+// (values < 1024), where formatLocalized falls back to just units.byteWord,
+// across locales. This does NOT exercise Indian-style digit grouping: a
+// ByteSize mantissa is never more than 4 significant digits (formatByteSize
+// never prints more), and Indian grouping only diverges from Western
+// grouping from the sixth digit onward, so the two are indistinguishable
+// anywhere this package can produce a mantissa — "1,023" either way. Only
+// the separator before "B" differs here.
+func TestFormatLocalizedBelowOneK(t *testing.T) {
+	cases := []struct {
+		tag  language.Tag
+		want string
+	}{
+		{language.English, "1,023B"},
+		{language.MustParse("hi-IN"), "1,023 B"},
+	}
+	for _, c := range cases {
+		p := message.NewPrinter(c.tag)
+		got := FormatLocalized(bytesize.ByteSize(1023), c.tag, p, 3)
+		if got != c.want {
+			t.Errorf("FormatLocalized(1023, %v) => %q, wanted %q", c.tag, got, c.want)
+		}
+	}
+}